@@ -3,11 +3,19 @@
 package log4go
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-  "os/exec"
   "path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 // This log writer sends output to a file
@@ -18,7 +26,23 @@ type DailyFileLogWriter struct {
 	// The opened file
 	filename string
 	filedate time.Time "UTC timestamp the file was created / rotated at"
-	file     *os.File
+	file     *MuxWriter
+
+	// Closed once the writer goroutine has drained rec and torn down file
+	done chan struct{}
+
+	// Guards against close(w.rec) panicking on a second Close() call
+	closeOnce sync.Once
+
+	// Tracks background compressor.Compress goroutines spawned by intRotate,
+	// so Close can wait for them instead of abandoning a compression
+	// mid-write on shutdown.
+	compressWG sync.WaitGroup
+
+	// Guards sighupCh/sighupStop below
+	sighupMu   sync.Mutex
+	sighupCh   chan os.Signal
+	sighupStop chan struct{}
 
 	// The logging format
 	format string
@@ -26,8 +50,234 @@ type DailyFileLogWriter struct {
 	// File header/trailer
 	header, trailer string
 
-	// How many old logfiles to keep
+	// Whether intRotate archives the old file at all. Independent of
+	// rotate_limit, which only bounds how old (in days) an archive may get
+	// before pruneOldArchives deletes it once archiving is on.
+	rotate bool
+
+	// Age in days beyond which old archives are pruned once rotate is enabled
 	rotate_limit uint64 "Number of days to keep, 0=all"
+
+	// Time-based rotation trigger, e.g. DailySchedule or HourlySchedule.
+	// nil disables time-based rotation entirely.
+	schedule RotationSchedule
+
+	// Rotate once the file reaches this many bytes, 0=unlimited
+	maxsize uint64
+
+	// Rotate once the file reaches this many lines, 0=unlimited
+	maxlines uint64
+
+	// Age in hours beyond which archives are pruned when schedule is an
+	// HourlySchedule, 0=all
+	maxhours uint64
+
+	// Compresses a rotated file in the background. GzipCompressor is used
+	// when nil.
+	compressor Compressor
+
+	// Permissions the log file is opened with
+	perm os.FileMode
+
+	// Size and line count of the currently open file
+	curSize  uint64
+	curLines uint64
+}
+
+// closeDrainTimeout bounds how long Close() waits for the writer goroutine
+// to finish draining already-buffered records before giving up.
+const closeDrainTimeout = 5 * time.Second
+
+// MuxWriter wraps an *os.File behind a mutex so that intRotate swapping in
+// a freshly-rotated fd can never race with another goroutine writing to the
+// old one — e.g. SetHeadFoot or a SIGHUP-triggered Rotate() firing while a
+// write is in flight.
+type MuxWriter struct {
+	mu sync.Mutex
+	fd *os.File
+}
+
+// NewMuxWriter wraps fd in a MuxWriter.
+func NewMuxWriter(fd *os.File) *MuxWriter {
+	return &MuxWriter{fd: fd}
+}
+
+// Write implements io.Writer by delegating to the current underlying file.
+func (m *MuxWriter) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fd.Write(p)
+}
+
+// SetFd closes the previous underlying file (after syncing it) and swaps
+// in fd, all under lock so no write ever lands on a closed fd.
+func (m *MuxWriter) SetFd(fd *os.File) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fd != nil {
+		m.fd.Sync()
+		m.fd.Close()
+	}
+	m.fd = fd
+}
+
+// Sync flushes the current underlying file to disk.
+func (m *MuxWriter) Sync() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fd.Sync()
+}
+
+// Close closes the current underlying file.
+func (m *MuxWriter) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fd.Close()
+}
+
+// Compressor archives a just-rotated log file, returning the path of the
+// compressed copy. Compress must not remove src until dst is durably on
+// disk, so a crash mid-compression never leaves retention pruning (or an
+// operator) looking at a half-written archive.
+type Compressor interface {
+	Compress(src string) (dst string, err error)
+
+	// Suffix returns the filename suffix Compress appends to src to build
+	// dst, e.g. ".gz" or ".zst" ("" for NoopCompressor). nextArchiveName
+	// uses it to tell whether a given sequence number is already taken by
+	// an archive compressed asynchronously in the background, so it never
+	// picks a name that a pending Compress call would then clobber.
+	Suffix() string
+}
+
+// GzipCompressor compresses with compress/gzip. It replaces shelling out to
+// the external "gzip" binary, which requires gzip on $PATH and doesn't
+// exist on Windows.
+type GzipCompressor struct{}
+
+func (GzipCompressor) Suffix() string { return ".gz" }
+
+func (c GzipCompressor) Compress(src string) (string, error) {
+	return compressFile(src, c.Suffix(), func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	})
+}
+
+// ZstdCompressor compresses with zstd, trading a bit more CPU for
+// meaningfully smaller archives than gzip on most log text.
+type ZstdCompressor struct{}
+
+func (ZstdCompressor) Suffix() string { return ".zst" }
+
+func (c ZstdCompressor) Compress(src string) (string, error) {
+	return compressFile(src, c.Suffix(), func(w io.Writer) io.WriteCloser {
+		enc, err := zstd.NewWriter(w)
+		if err != nil {
+			// Only fails on invalid options, and we pass none.
+			panic(err)
+		}
+		return enc
+	})
+}
+
+// NoopCompressor leaves the rotated file uncompressed, for operators who
+// compress or ship logs with an external tool.
+type NoopCompressor struct{}
+
+func (NoopCompressor) Suffix() string { return "" }
+
+func (NoopCompressor) Compress(src string) (string, error) {
+	return src, nil
+}
+
+// compressFile streams src through the writer built by newEncoder into
+// "<src><suffix>.tmp", fsyncs it, and atomically renames it into place
+// before deleting src. The .tmp name (and atomic rename) keep a
+// half-written archive from ever being visible under its final name, so
+// SetRotateLimit's pruning never mistakes a partial file for a real one.
+func compressFile(src, suffix string, newEncoder func(io.Writer) io.WriteCloser) (string, error) {
+	dst := src + suffix
+	tmp := dst + ".tmp"
+
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0660)
+	if err != nil {
+		return "", err
+	}
+
+	enc := newEncoder(out)
+	if _, err := io.Copy(enc, in); err != nil {
+		enc.Close()
+		out.Close()
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Remove(src); err != nil {
+		return dst, err
+	}
+	return dst, nil
+}
+
+// RotationSchedule decides when a DailyFileLogWriter should roll over to a
+// new file based on wall-clock time, and how the archived copy of the old
+// file should be named. DailySchedule and HourlySchedule are the built-in
+// implementations; callers may supply their own (e.g. weekly, per-shift).
+type RotationSchedule interface {
+	// ShouldRotate reports whether a file opened/rotated at prev should be
+	// rotated again now that the time is now.
+	ShouldRotate(prev, now time.Time) bool
+
+	// ArchiveSuffix returns the timestamp component of the archived
+	// filename for a file rotated at t, e.g. "2006-01-02" or
+	// "2006-01-02-15". It must be unique per rotation period so that
+	// archives from different periods never collide.
+	ArchiveSuffix(t time.Time) string
+}
+
+// DailySchedule rotates once per UTC calendar day.
+type DailySchedule struct{}
+
+func (DailySchedule) ShouldRotate(prev, now time.Time) bool {
+	return now.Format("2006-01-02") != prev.Format("2006-01-02")
+}
+
+func (DailySchedule) ArchiveSuffix(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// HourlySchedule rotates once per UTC hour, for high-volume services that
+// want their logs sliced into hourly buckets.
+type HourlySchedule struct{}
+
+func (HourlySchedule) ShouldRotate(prev, now time.Time) bool {
+	return now.Format("2006-01-02-15") != prev.Format("2006-01-02-15")
+}
+
+func (HourlySchedule) ArchiveSuffix(t time.Time) string {
+	return t.Format("2006-01-02-15")
 }
 
 // This is the DailyFileLogWriter's output method
@@ -35,9 +285,37 @@ func (w *DailyFileLogWriter) LogWrite(rec *LogRecord) {
 	w.rec <- rec
 }
 
+// Close stops accepting new records and waits (up to closeDrainTimeout) for
+// the writer goroutine to flush everything already buffered in rec before
+// returning, so a burst of log calls right before shutdown isn't lost. It
+// then waits (again up to closeDrainTimeout) for any background
+// compressions still in flight from earlier rotations, so a process that
+// exits shortly after Close never abandons a rotated file mid-compression,
+// leaving an orphaned ".tmp" behind. It also tears down any SIGHUP handler
+// installed via HandleSIGHUP, so a signal delivered after Close never leaks
+// the listener goroutine. Safe to call more than once; only the first call
+// does anything.
 func (w *DailyFileLogWriter) Close() {
-	close(w.rec)
-	w.file.Sync()
+	w.closeOnce.Do(func() {
+		w.disableSIGHUP()
+		close(w.rec)
+		select {
+		case <-w.done:
+		case <-time.After(closeDrainTimeout):
+			fmt.Fprintf(os.Stderr, "DailyFileLogWriter(%q): timed out waiting for buffered records to flush\n", w.filename)
+		}
+
+		compressDone := make(chan struct{})
+		go func() {
+			w.compressWG.Wait()
+			close(compressDone)
+		}()
+		select {
+		case <-compressDone:
+		case <-time.After(closeDrainTimeout):
+			fmt.Fprintf(os.Stderr, "DailyFileLogWriter(%q): timed out waiting for background compression to finish\n", w.filename)
+		}
+	})
 }
 
 // NewDailyFileLogWriter creates a new LogWriter which writes to the given file and
@@ -50,13 +328,30 @@ func (w *DailyFileLogWriter) Close() {
 // The standard log-line format is:
 //   [%D %T] [%L] (%S) %M
 func NewDailyFileLogWriter(fname string, rotate_limit uint64) *DailyFileLogWriter {
+	return newDailyFileLogWriter(fname, rotate_limit, nil)
+}
+
+// newDailyFileLogWriter is the shared constructor behind NewDailyFileLogWriter
+// and Configure. configure, if non-nil, runs against the struct before the
+// initial intRotate() and writer goroutine start, so options like
+// SetFilePerm/SetRotate take effect on the very first file open instead of
+// only from the next rotation onward.
+func newDailyFileLogWriter(fname string, rotate_limit uint64, configure func(*DailyFileLogWriter)) *DailyFileLogWriter {
 	w := &DailyFileLogWriter{
 		rec:          make(chan *LogRecord, LogBufferLength),
 		rot:          make(chan bool),
+		done:         make(chan struct{}),
 		filename:     fname,
 		filedate:     time.Now().UTC(),
 		format:       "[%D %T] [%L] (%S) %M",
+		rotate:       true,
 		rotate_limit: rotate_limit,
+		schedule:     DailySchedule{},
+		perm:         0660,
+	}
+
+	if configure != nil {
+		configure(w)
 	}
 
 	// open the file for the first time
@@ -72,6 +367,7 @@ func NewDailyFileLogWriter(fname string, rotate_limit uint64) *DailyFileLogWrite
 				fmt.Fprint(w.file, FormatLogRecord(w.trailer, &LogRecord{Created: time.Now()}))
 				w.file.Close()
 			}
+			close(w.done)
 		}()
 
 		for {
@@ -85,7 +381,7 @@ func NewDailyFileLogWriter(fname string, rotate_limit uint64) *DailyFileLogWrite
 				if !ok {
 					return
 				}
-				if time.Now().UTC().Day() != w.filedate.Day() {
+				if w.shouldRotate() {
 					if err := w.intRotate(); err != nil {
 						fmt.Fprintf(os.Stderr, "DailyFileLogWriter(%q): %s\n", w.filename, err)
 						return
@@ -93,11 +389,13 @@ func NewDailyFileLogWriter(fname string, rotate_limit uint64) *DailyFileLogWrite
 				}
 
 				// Perform the write
-				_, err := fmt.Fprint(w.file, FormatLogRecord(w.format, rec))
+				n, err := fmt.Fprint(w.file, FormatLogRecord(w.format, rec))
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "DailyFileLogWriter(%q): %s\n", w.filename, err)
 					return
 				}
+				w.curLines++
+				w.curSize += uint64(n)
 			}
 		}
 	}()
@@ -105,53 +403,235 @@ func NewDailyFileLogWriter(fname string, rotate_limit uint64) *DailyFileLogWrite
 	return w
 }
 
-// Request that the logs rotate
+// shouldRotate reports whether the schedule, size, or line limits configured
+// via SetRotateDaily, SetRotateHourly, SetRotationSchedule, SetRotateMaxSize,
+// and SetRotateMaxLines have been reached.
+func (w *DailyFileLogWriter) shouldRotate() bool {
+	if w.schedule != nil && w.schedule.ShouldRotate(w.filedate, time.Now().UTC()) {
+		return true
+	}
+	if w.maxsize > 0 && w.curSize >= w.maxsize {
+		return true
+	}
+	if w.maxlines > 0 && w.curLines >= w.maxlines {
+		return true
+	}
+	return false
+}
+
+// Request that the logs rotate. A no-op once the writer is closed, so a
+// SIGHUP (or any other caller of Rotate) racing with or arriving after
+// Close never blocks forever sending to a rot channel nobody reads anymore.
 func (w *DailyFileLogWriter) Rotate() {
-	w.rot <- true
+	select {
+	case w.rot <- true:
+	case <-w.done:
+	}
 }
 
 // If this is called in a threaded context, it MUST be synchronized
 func (w *DailyFileLogWriter) intRotate() error {
-	// Close any log file that may be open
+	// Write the trailer to whatever file is currently open. The old fd
+	// itself isn't closed until the new one is swapped in via SetFd below,
+	// so a concurrent SetHeadFoot or SIGHUP-triggered rotation can never
+	// observe (or write to) a closed fd.
 	if w.file != nil {
 		fmt.Fprint(w.file, FormatLogRecord(w.trailer, &LogRecord{Created: time.Now()}))
-		w.file.Close()
 	}
 
-	// If we are keeping log files, move it to the next available number
-	if fi, err := os.Lstat(w.filename); (nil != fi || os.IsExist(err)) && w.rotate_limit > 0  {
-		// <filename>.YYYY-MM-DD
-		fname := fmt.Sprintf("%s.%04d-%02d-%02d", w.filename, w.filedate.Year(), w.filedate.Month(), w.filedate.Day())
+	// If we are archiving log files, move it to the next available number.
+	// This is gated on w.rotate alone: rotate_limit only bounds retention
+	// (how many days of archives pruneOldArchives keeps), so a caller who wants
+	// unlimited retention (rotate_limit == 0) with size/line/hourly
+	// rotation still gets real archives instead of the same file being
+	// endlessly reopened over threshold.
+	if fi, err := os.Lstat(w.filename); (nil != fi || os.IsExist(err)) && w.rotate {
+		// <filename>.YYYY-MM-DD.NNN, the sequence number prevents a later
+		// size- or line-triggered rotation on the same day from clobbering
+		// an archive already made earlier that day.
+		fname := w.nextArchiveName(w.filedate)
 
 		// Rename the file to its newfound home
 		if err := os.Rename(w.filename, fname); err != nil {
 			return fmt.Errorf("Rotate: %s\n", err)
-		} else {
-      // Debugging:
-      
-      // fmt.Fprintf(os.Stderr, "DailyFileLogWriter[intRotate](%v, %v) -> %v\n", w.filename, w.filedate, fname)
 		}
-    
-    cmd := exec.Command("gzip", "--fast", "--force", fname)
-    cmd.Dir = filepath.Dir(fname)
-    if err := cmd.Run(); nil != err {
-      return err
-    }
+
+		// Compress in the background so a large archive never stalls the
+		// writer goroutine's log writes.
+		compressor := w.compressor
+		if compressor == nil {
+			compressor = GzipCompressor{}
+		}
+		w.compressWG.Add(1)
+		go func(path string) {
+			defer w.compressWG.Done()
+			if _, err := compressor.Compress(path); err != nil {
+				fmt.Fprintf(os.Stderr, "DailyFileLogWriter(%q): compress %q: %s\n", w.filename, path, err)
+			}
+		}(fname)
+
+		w.pruneOldArchives(time.Now().UTC())
 	}
 
 	// Open the log file
-	fd, err := os.OpenFile(w.filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	fd, err := os.OpenFile(w.filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, w.perm)
 	if err != nil {
 		return err
 	}
 	w.filedate = time.Now().UTC()
-	w.file = fd
+	if w.file == nil {
+		w.file = NewMuxWriter(fd)
+	} else {
+		w.file.SetFd(fd)
+	}
+
+	// Re-derive curSize (and, if line-based rotation is enabled, curLines)
+	// from whatever is already on disk so that rotation thresholds keep
+	// counting correctly across process restarts instead of starting at 0
+	// against a non-empty file.
+	w.curSize = 0
+	w.curLines = 0
+	if fi, err := os.Stat(w.filename); err == nil {
+		w.curSize = uint64(fi.Size())
+		if w.maxlines > 0 {
+			if n, err := countFileLines(w.filename); err == nil {
+				w.curLines = n
+			}
+		}
+	}
 
 	fmt.Fprint(w.file, FormatLogRecord(w.header, &LogRecord{Created: w.filedate}))
 
 	return nil
 }
 
+// nextArchiveName returns the path to archive the current log file to for
+// the given date, appending a zero-padded sequence number (starting at 001)
+// so multiple rotations within the same schedule period don't overwrite one
+// another. The timestamp component itself comes from the active schedule
+// (DailySchedule, HourlySchedule, ...) so hourly archives don't collide on
+// their calendar day. It also probes the active compressor's Suffix() (not
+// a hardcoded ".gz"), since a sequence number compressed in the background
+// by e.g. ZstdCompressor only ever exists on disk under its ".zst" name.
+func (w *DailyFileLogWriter) nextArchiveName(date time.Time) string {
+	schedule := w.schedule
+	if schedule == nil {
+		schedule = DailySchedule{}
+	}
+	compressor := w.compressor
+	if compressor == nil {
+		compressor = GzipCompressor{}
+	}
+	suffix := compressor.Suffix()
+
+	base := fmt.Sprintf("%s.%s", w.filename, schedule.ArchiveSuffix(date))
+	for seq := 1; ; seq++ {
+		candidate := fmt.Sprintf("%s.%03d", base, seq)
+		if _, err := os.Lstat(candidate); !os.IsNotExist(err) {
+			continue
+		}
+		if suffix != "" {
+			if _, err := os.Lstat(candidate + suffix); !os.IsNotExist(err) {
+				continue
+			}
+		}
+		return candidate
+	}
+}
+
+// pruneOldArchives deletes archived copies of w.filename (uncompressed,
+// ".gz" and ".zst" forms alike) whose embedded schedule timestamp is
+// older than the configured retention window: w.rotate_limit days for
+// DailySchedule (and any other/custom schedule), or w.maxhours hours for
+// HourlySchedule. A retention of 0 keeps everything. Failures are logged
+// to stderr rather than returned, so a permissions problem pruning old
+// archives never takes down the writer goroutine.
+func (w *DailyFileLogWriter) pruneOldArchives(now time.Time) {
+	limit := w.rotate_limit
+	period := 24 * time.Hour
+	if _, ok := w.schedule.(HourlySchedule); ok {
+		limit = w.maxhours
+		period = time.Hour
+	}
+	if limit == 0 {
+		return
+	}
+
+	dir := filepath.Dir(w.filename)
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	cutoff := now.Add(-time.Duration(limit) * period)
+	prefix := filepath.Base(w.filename) + "."
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "DailyFileLogWriter(%q): prune: %s\n", w.filename, err)
+			return nil
+		}
+		if info.IsDir() || !strings.HasPrefix(info.Name(), prefix) {
+			return nil
+		}
+		t, ok := parseArchiveTimestamp(info.Name()[len(prefix):])
+		if !ok || !t.Before(cutoff) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			fmt.Fprintf(os.Stderr, "DailyFileLogWriter(%q): failed to prune %q: %s\n", w.filename, path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "DailyFileLogWriter(%q): prune: %s\n", w.filename, err)
+	}
+}
+
+// parseArchiveTimestamp extracts the schedule timestamp from an archived
+// filename's suffix (everything after "<base>."), e.g. "2006-01-02.001.gz",
+// "2006-01-02-15.001", or a bare "2006-01-02" from before sequence numbers
+// existed.
+func parseArchiveTimestamp(suffix string) (time.Time, bool) {
+	suffix = strings.TrimSuffix(strings.TrimSuffix(suffix, ".gz"), ".zst")
+	if idx := strings.LastIndex(suffix, "."); idx >= 0 {
+		if _, err := strconv.Atoi(suffix[idx+1:]); err == nil {
+			suffix = suffix[:idx]
+		}
+	}
+	if t, err := time.Parse("2006-01-02-15", suffix); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse("2006-01-02", suffix); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// countFileLines counts the number of newlines in fname, used to resume
+// line-based rotation counts across restarts without loading the whole
+// file into memory.
+func countFileLines(fname string) (uint64, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var count uint64
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		count += uint64(bytes.Count(buf[:n], []byte{'\n'}))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
 // Set the logging format (chainable).  Must be called before the first log
 // message is written.
 func (w *DailyFileLogWriter) SetFormat(format string) *DailyFileLogWriter {
@@ -170,15 +650,229 @@ func (w *DailyFileLogWriter) SetHeadFoot(head, foot string) *DailyFileLogWriter
 	return w
 }
 
-// SetRotateLimit changes whether or not the old logs are kept. (chainable) Must be
-// called before the first log message is written.  If rotate is 0, the
-// files are overwritten; otherwise, they are rotated to another file before the
-// new log is opened.
+// SetRotateLimit changes how many days of archived logs pruneOldArchives
+// keeps once rotation is enabled (see SetRotate): archives whose timestamp is
+// older than rotate_limit days are deleted, however many there are.
+// (chainable) Must be called before the first log message is written. 0
+// keeps archives forever.
 func (w *DailyFileLogWriter) SetRotateLimit(rotate_limit uint64) *DailyFileLogWriter {
 	w.rotate_limit = rotate_limit
 	return w
 }
 
+// SetRotate changes whether a rotated file is archived at all. (chainable)
+// The initial file is already opened (and, if it pre-exists, possibly
+// archived) by the time NewDailyFileLogWriter returns, so calling this on
+// its result only takes effect starting from the next rotation; to have it
+// apply to the very first open (as Configure does), set it before the first
+// intRotate runs. Enabled by default; disabling it makes every rotation
+// simply reopen w.filename and keep appending to its existing contents
+// instead of renaming/compressing them away. Unlike SetRotateLimit, this
+// does not affect retention of archives already on disk.
+func (w *DailyFileLogWriter) SetRotate(rotate bool) *DailyFileLogWriter {
+	w.rotate = rotate
+	return w
+}
+
+// SetRotateDaily changes whether the log file rotates when the UTC day
+// changes. (chainable) Enabled by default to preserve the writer's
+// original behavior.
+func (w *DailyFileLogWriter) SetRotateDaily(daily bool) *DailyFileLogWriter {
+	if daily {
+		w.schedule = DailySchedule{}
+	} else {
+		w.schedule = nil
+	}
+	return w
+}
+
+// SetRotateHourly changes whether the log file rotates every UTC hour
+// instead of every day. (chainable) Passing false disables time-based
+// rotation entirely; it does not switch back to daily rotation (use
+// SetRotateDaily for that). Use SetMaxHours to bound how many hours of
+// hourly archives are kept.
+func (w *DailyFileLogWriter) SetRotateHourly(hourly bool) *DailyFileLogWriter {
+	if hourly {
+		w.schedule = HourlySchedule{}
+	} else {
+		w.schedule = nil
+	}
+	return w
+}
+
+// SetMaxHours sets how many hours of archives are kept when SetRotateHourly
+// is enabled: archives older than maxhours hours are deleted. (chainable) 0
+// (the default) keeps all of them.
+func (w *DailyFileLogWriter) SetMaxHours(maxhours uint64) *DailyFileLogWriter {
+	w.maxhours = maxhours
+	return w
+}
+
+// SetRotationSchedule installs a custom RotationSchedule (chainable),
+// overriding SetRotateDaily/SetRotateHourly for callers that need rotation
+// on a different cadence, e.g. weekly or per-shift.
+func (w *DailyFileLogWriter) SetRotationSchedule(schedule RotationSchedule) *DailyFileLogWriter {
+	w.schedule = schedule
+	return w
+}
+
+// SetFilePerm changes the permissions the log file is opened with.
+// (chainable) The initial file is already opened by the time
+// NewDailyFileLogWriter returns, so calling this on its result only takes
+// effect starting from the next rotation; to have it apply to the very
+// first open (as Configure does), set it before the first intRotate runs.
+// Applies only when a rotation creates the file anew; with SetRotate(false)
+// the existing file is reopened and keeps its mode. Defaults to 0660.
+func (w *DailyFileLogWriter) SetFilePerm(perm os.FileMode) *DailyFileLogWriter {
+	w.perm = perm
+	return w
+}
+
+// SetCompressor overrides the archive compressor used after rotation
+// (chainable); GzipCompressor is used by default. See ZstdCompressor and
+// NoopCompressor for alternatives.
+func (w *DailyFileLogWriter) SetCompressor(compressor Compressor) *DailyFileLogWriter {
+	w.compressor = compressor
+	return w
+}
+
+// SetRotateMaxSize rotates the log file once it reaches maxsize bytes.
+// (chainable) 0 (the default) disables size-based rotation.
+func (w *DailyFileLogWriter) SetRotateMaxSize(maxsize uint64) *DailyFileLogWriter {
+	w.maxsize = maxsize
+	return w
+}
+
+// SetRotateMaxLines rotates the log file once it reaches maxlines lines.
+// (chainable) 0 (the default) disables line-based rotation.
+func (w *DailyFileLogWriter) SetRotateMaxLines(maxlines uint64) *DailyFileLogWriter {
+	w.maxlines = maxlines
+	return w
+}
+
+var (
+	writersMu sync.Mutex
+	writers   = map[string]*DailyFileLogWriter{}
+)
+
+// WriterConfig is the JSON shape accepted by Configure and
+// LoadConfiguration, mirroring beego's file-logger configuration so ops
+// teams can reconfigure rotation without recompiling:
+//
+//	{"filename":"app.log","maxlines":100000,"maxsize":1048576,"daily":true,
+//	 "maxdays":7,"rotate":true,"perm":"0640","dirperm":"0750"}
+type WriterConfig struct {
+	Filename string `json:"filename"`
+	MaxLines uint64 `json:"maxlines"`
+	MaxSize  uint64 `json:"maxsize"`
+	Daily    bool   `json:"daily"`
+	MaxDays  uint64 `json:"maxdays"`
+	Rotate   bool   `json:"rotate"`
+	Perm     string `json:"perm"`
+	DirPerm  string `json:"dirperm"`
+}
+
+// Configure builds a DailyFileLogWriter from a WriterConfig JSON document
+// and registers it under name, replacing (and closing) any writer already
+// registered under that name. Look it up later with Writer(name).
+func Configure(name string, config []byte) (*DailyFileLogWriter, error) {
+	cfg := WriterConfig{
+		Daily:  true,
+		Rotate: true,
+	}
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return nil, fmt.Errorf("Configure(%q): %s", name, err)
+	}
+
+	dirperm, err := parsePerm(cfg.DirPerm, 0750)
+	if err != nil {
+		return nil, fmt.Errorf("Configure(%q): dirperm: %s", name, err)
+	}
+	perm, err := parsePerm(cfg.Perm, 0660)
+	if err != nil {
+		return nil, fmt.Errorf("Configure(%q): perm: %s", name, err)
+	}
+
+	if dir := filepath.Dir(cfg.Filename); dir != "." {
+		if err := os.MkdirAll(dir, dirperm); err != nil {
+			return nil, fmt.Errorf("Configure(%q): %s", name, err)
+		}
+	}
+
+	// Apply perm/rotate/daily/maxlines/maxsize via the configure hook, not
+	// as post-construction setters: NewDailyFileLogWriter's first intRotate
+	// (which opens, and possibly archives, cfg.Filename) runs before any
+	// setter called on its return value ever would, so perm and rotate
+	// would otherwise be silently ignored for that first open.
+	w := newDailyFileLogWriter(cfg.Filename, cfg.MaxDays, func(w *DailyFileLogWriter) {
+		w.SetFilePerm(perm)
+		w.SetRotate(cfg.Rotate)
+		if cfg.Rotate {
+			w.SetRotateDaily(cfg.Daily).SetRotateMaxLines(cfg.MaxLines).SetRotateMaxSize(cfg.MaxSize)
+		} else {
+			w.SetRotateDaily(false).SetRotateMaxLines(0).SetRotateMaxSize(0)
+		}
+	})
+	if w == nil {
+		return nil, fmt.Errorf("Configure(%q): failed to open %q", name, cfg.Filename)
+	}
+
+	writersMu.Lock()
+	old, hadOld := writers[name]
+	writers[name] = w
+	writersMu.Unlock()
+
+	// Close the replaced writer (which can block up to closeDrainTimeout
+	// draining it) after releasing writersMu, so a slow close never stalls
+	// Configure/Writer calls for unrelated names.
+	if hadOld && old != w {
+		old.Close()
+	}
+
+	return w, nil
+}
+
+// LoadConfiguration configures one writer per top-level key of a JSON
+// document of the form {"<name>": <WriterConfig>, ...}, via Configure.
+func LoadConfiguration(config []byte) (map[string]*DailyFileLogWriter, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(config, &raw); err != nil {
+		return nil, fmt.Errorf("LoadConfiguration: %s", err)
+	}
+
+	result := make(map[string]*DailyFileLogWriter, len(raw))
+	for name, body := range raw {
+		w, err := Configure(name, body)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = w
+	}
+	return result, nil
+}
+
+// Writer returns the DailyFileLogWriter previously registered under name by
+// Configure or LoadConfiguration.
+func Writer(name string) (*DailyFileLogWriter, bool) {
+	writersMu.Lock()
+	defer writersMu.Unlock()
+	w, ok := writers[name]
+	return w, ok
+}
+
+// parsePerm parses an octal permission string (e.g. "0640") as accepted by
+// WriterConfig.Perm/DirPerm, falling back to def when s is empty.
+func parsePerm(s string, def os.FileMode) (os.FileMode, error) {
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(s, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid permissions %q: %s", s, err)
+	}
+	return os.FileMode(n), nil
+}
+
 // NewXMLLogWriter is a utility method for creating a DailyFileLogWriter set up to
 // output XML record log messages instead of line-based ones.
 func NewDailyXMLLogWriter(fname string, rotate_limit uint64) *DailyFileLogWriter {
@@ -0,0 +1,454 @@
+// Copyright (C) 2010, Kyle Lemons <kyle@kylelemons.net>.  All rights reserved.
+
+package log4go
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestHourlySchedule(t *testing.T) {
+	var sched HourlySchedule
+
+	prev, err := time.Parse("2006-01-02-15", "2024-01-10-09")
+	if err != nil {
+		t.Fatalf("time.Parse: %s", err)
+	}
+
+	if sched.ShouldRotate(prev, prev.Add(30*time.Minute)) {
+		t.Errorf("ShouldRotate: expected no rotation within the same hour")
+	}
+	if !sched.ShouldRotate(prev, prev.Add(time.Hour)) {
+		t.Errorf("ShouldRotate: expected rotation once the hour changes")
+	}
+	if got, want := sched.ArchiveSuffix(prev), "2024-01-10-09"; got != want {
+		t.Errorf("ArchiveSuffix: got %q, want %q", got, want)
+	}
+}
+
+func TestPruneOldArchivesHourly(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log4go-prune-hourly")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := &DailyFileLogWriter{
+		filename: filepath.Join(dir, "foo.log"),
+		maxhours: 2,
+		schedule: HourlySchedule{},
+	}
+
+	now, err := time.Parse("2006-01-02-15", "2024-01-10-09")
+	if err != nil {
+		t.Fatalf("time.Parse: %s", err)
+	}
+
+	keep := []string{
+		"foo.log.2024-01-10-08.001.gz", // 1 hour old, within the 2-hour limit
+		"foo.log.2024-01-10-07.001.gz", // exactly at the limit, not yet older than it
+	}
+	remove := []string{
+		"foo.log.2024-01-10-06.001.gz", // older than the 2-hour limit
+	}
+
+	for _, name := range append(append([]string{}, keep...), remove...) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatalf("WriteFile(%q): %s", name, err)
+		}
+	}
+
+	w.pruneOldArchives(now)
+
+	for _, name := range keep {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %q to be kept, but it's gone: %s", name, err)
+		}
+	}
+	for _, name := range remove {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %q to be pruned, but it still exists", name)
+		}
+	}
+}
+
+func TestNextArchiveNameSkipsExistingCompressedArchives(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log4go-nextarchive")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := &DailyFileLogWriter{
+		filename:   filepath.Join(dir, "foo.log"),
+		schedule:   DailySchedule{},
+		compressor: ZstdCompressor{},
+	}
+
+	date, err := time.Parse("2006-01-02", "2024-01-10")
+	if err != nil {
+		t.Fatalf("time.Parse: %s", err)
+	}
+
+	// Simulate sequence 001 already archived and compressed to .zst, as
+	// compressFile's atomic rename leaves it, with the bare ".001" (what a
+	// .gz-only check would look for) long gone.
+	taken := filepath.Join(dir, "foo.log.2024-01-10.001.zst")
+	if err := os.WriteFile(taken, []byte("x"), 0644); err != nil {
+		t.Fatalf("WriteFile(%q): %s", taken, err)
+	}
+
+	got := w.nextArchiveName(date)
+	want := filepath.Join(dir, "foo.log.2024-01-10.002")
+	if got != want {
+		t.Errorf("nextArchiveName: got %q, want %q (collided with an already-compressed .zst archive)", got, want)
+	}
+}
+
+func TestCompressorsRoundTrip(t *testing.T) {
+	const content = "line one\nline two\nline three\n"
+
+	decoders := map[string]func(r io.Reader) (io.Reader, error){
+		".gz": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		".zst": func(r io.Reader) (io.Reader, error) {
+			dec, err := zstd.NewReader(r)
+			return dec.IOReadCloser(), err
+		},
+		"": func(r io.Reader) (io.Reader, error) { return r, nil },
+	}
+
+	for _, compressor := range []Compressor{GzipCompressor{}, ZstdCompressor{}, NoopCompressor{}} {
+		compressor := compressor
+		t.Run(compressor.Suffix(), func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "log4go-compress")
+			if err != nil {
+				t.Fatalf("MkdirTemp: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			src := filepath.Join(dir, "foo.log.2024-01-10.001")
+			if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+				t.Fatalf("WriteFile(%q): %s", src, err)
+			}
+
+			dst, err := compressor.Compress(src)
+			if err != nil {
+				t.Fatalf("Compress: %s", err)
+			}
+			if want := src + compressor.Suffix(); dst != want {
+				t.Errorf("Compress: got dst %q, want %q", dst, want)
+			}
+
+			// NoopCompressor returns src itself as dst, so there's nothing to
+			// clean up; the real compressors must remove the uncompressed
+			// source once the compressed copy is durably on disk.
+			if dst != src {
+				if _, err := os.Stat(src); !os.IsNotExist(err) {
+					t.Errorf("expected source %q to be removed after Compress, got err=%v", src, err)
+				}
+			}
+			if _, err := os.Stat(dst + ".tmp"); !os.IsNotExist(err) {
+				t.Errorf("expected %q.tmp to be gone after Compress, got err=%v", dst, err)
+			}
+
+			f, err := os.Open(dst)
+			if err != nil {
+				t.Fatalf("Open(%q): %s", dst, err)
+			}
+			defer f.Close()
+
+			r, err := decoders[compressor.Suffix()](f)
+			if err != nil {
+				t.Fatalf("decode %q: %s", dst, err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll: %s", err)
+			}
+			if !bytes.Equal(got, []byte(content)) {
+				t.Errorf("round-tripped content: got %q, want %q", got, content)
+			}
+		})
+	}
+}
+
+func TestConfigureRotateForeverWithUnlimitedRetention(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log4go-configure")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "app.log")
+	config := []byte(`{"filename":` + strconv.Quote(fname) + `,"rotate":true,"daily":true,"maxdays":0}`)
+
+	w, err := Configure("app", config)
+	if err != nil {
+		t.Fatalf("Configure: %s", err)
+	}
+	defer w.Close()
+
+	if !w.rotate {
+		t.Errorf("rotate: got false, want true for {\"rotate\":true}")
+	}
+	if w.rotate_limit != 0 {
+		t.Errorf("rotate_limit: got %d, want 0 (unlimited retention, not \"never rotate\")", w.rotate_limit)
+	}
+	if w.schedule == nil {
+		t.Errorf("schedule: got nil, want DailySchedule for {\"daily\":true}")
+	}
+}
+
+func TestConfigureDefaultsRotateTrueWhenOmitted(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log4go-configure-rotate-default")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// "rotate" is entirely absent, as a minimal config would leave it:
+	// Configure must still default to beego's rotate-on behavior, the same
+	// as NewDailyFileLogWriter.
+	fname := filepath.Join(dir, "app.log")
+	config := []byte(`{"filename":` + strconv.Quote(fname) + `}`)
+
+	w, err := Configure("app-rotate-default", config)
+	if err != nil {
+		t.Fatalf("Configure: %s", err)
+	}
+	defer w.Close()
+
+	if !w.rotate {
+		t.Errorf("rotate: got false, want true when \"rotate\" is omitted from the config")
+	}
+	if w.schedule == nil {
+		t.Errorf("schedule: got nil, want DailySchedule when \"rotate\" is omitted from the config")
+	}
+}
+
+func TestConfigureAppliesPermToInitialOpen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log4go-configure-perm")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A brand-new file: perm must apply to the very first open, not just
+	// to files opened by a later rotation.
+	fname := filepath.Join(dir, "app.log")
+	config := []byte(`{"filename":` + strconv.Quote(fname) + `,"perm":"0600","rotate":false}`)
+	w, err := Configure("app-perm", config)
+	if err != nil {
+		t.Fatalf("Configure: %s", err)
+	}
+	defer w.Close()
+
+	fi, err := os.Stat(fname)
+	if err != nil {
+		t.Fatalf("Stat(%q): %s", fname, err)
+	}
+	if got, want := fi.Mode().Perm(), os.FileMode(0600); got != want {
+		t.Errorf("initial file perm: got %o, want %o", got, want)
+	}
+}
+
+func TestConfigureRotateFalseDoesNotArchivePreexistingFileOnStartup(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log4go-configure-norotate")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A pre-existing file: "rotate":false must be honored on the initial
+	// intRotate too, not only starting from the next rotation.
+	fname := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(fname, []byte("pre-existing\n"), 0644); err != nil {
+		t.Fatalf("WriteFile(%q): %s", fname, err)
+	}
+
+	config := []byte(`{"filename":` + strconv.Quote(fname) + `,"rotate":false}`)
+	w, err := Configure("app-norotate", config)
+	if err != nil {
+		t.Fatalf("Configure: %s", err)
+	}
+	defer w.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir(%q): %s", dir, err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected the pre-existing file to stay in place under \"rotate\":false, got %d entries in %s", len(entries), dir)
+	}
+}
+
+func TestRotateAfterCloseDoesNotDeadlock(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log4go-rotate-after-close")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := NewDailyFileLogWriter(filepath.Join(dir, "foo.log"), 0)
+	if w == nil {
+		t.Fatal("NewDailyFileLogWriter returned nil")
+	}
+	w.Close()
+
+	done := make(chan struct{})
+	go func() {
+		w.Rotate()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Rotate() blocked forever after Close()")
+	}
+}
+
+func TestDoubleCloseDoesNotPanic(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log4go-double-close")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := NewDailyFileLogWriter(filepath.Join(dir, "foo.log"), 0)
+	if w == nil {
+		t.Fatal("NewDailyFileLogWriter returned nil")
+	}
+	w.Close()
+	w.Close()
+}
+
+func TestRotateMaxLinesArchivesOldFile(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log4go-maxlines")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "foo.log")
+	w := NewDailyFileLogWriter(fname, 0)
+	if w == nil {
+		t.Fatal("NewDailyFileLogWriter returned nil")
+	}
+	w.SetRotateDaily(false).SetRotateMaxLines(2)
+
+	for i := 0; i < 5; i++ {
+		w.LogWrite(&LogRecord{Created: time.Now(), Message: "hello"})
+	}
+	w.Close()
+
+	// Close waits for background compression to finish, so by the time it
+	// returns the archive must exist under its final, compressed name -
+	// not just as a renamed-but-not-yet-compressed file.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir(%q): %s", dir, err)
+	}
+	compressed := 0
+	for _, e := range entries {
+		if e.Name() != "foo.log" {
+			if !strings.HasSuffix(e.Name(), GzipCompressor{}.Suffix()) {
+				t.Errorf("expected archive %q to be compressed (suffix %q), found uncompressed or partial file", e.Name(), GzipCompressor{}.Suffix())
+			}
+			compressed++
+		}
+	}
+	if compressed == 0 {
+		t.Errorf("expected SetRotateMaxLines(2) to archive and compress the file after 5 writes, found no archive in %v", entries)
+	}
+}
+
+func TestIntRotateResumesSizeAndLineCountsAcrossRestart(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log4go-resume")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "foo.log")
+	content := "line one\nline two\nline three\n"
+	if err := os.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile(%q): %s", fname, err)
+	}
+
+	// rotate is left at its zero value (false) so intRotate reopens fname
+	// in place instead of archiving it away first, mirroring a process
+	// restart against a file nothing has rotated yet.
+	w := &DailyFileLogWriter{
+		filename: fname,
+		maxlines: 10,
+		perm:     0660,
+	}
+	if err := w.intRotate(); err != nil {
+		t.Fatalf("intRotate: %s", err)
+	}
+	defer w.file.Close()
+
+	if got, want := w.curSize, uint64(len(content)); got != want {
+		t.Errorf("curSize after restart: got %d, want %d", got, want)
+	}
+	if got, want := w.curLines, uint64(3); got != want {
+		t.Errorf("curLines after restart: got %d, want %d", got, want)
+	}
+}
+
+func TestPruneOldArchivesDaily(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log4go-prune")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := &DailyFileLogWriter{
+		filename:     filepath.Join(dir, "foo.log"),
+		rotate_limit: 2,
+		schedule:     DailySchedule{},
+	}
+
+	now, err := time.Parse("2006-01-02", "2024-01-10")
+	if err != nil {
+		t.Fatalf("time.Parse: %s", err)
+	}
+
+	keep := []string{
+		"foo.log.2024-01-09.001.gz", // 1 day old, within the 2-day limit
+		"foo.log.2024-01-08.001.gz", // exactly at the limit, not yet older than it
+	}
+	remove := []string{
+		"foo.log.2024-01-07.001.gz", // older than the 2-day limit
+		"foo.log.2024-01-01.001",    // much older, uncompressed
+	}
+
+	for _, name := range append(append([]string{}, keep...), remove...) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatalf("WriteFile(%q): %s", name, err)
+		}
+	}
+
+	w.pruneOldArchives(now)
+
+	for _, name := range keep {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %q to be kept, but it's gone: %s", name, err)
+		}
+	}
+	for _, name := range remove {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %q to be pruned, but it still exists", name)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+//go:build !windows
+
+// Copyright (C) 2010, Kyle Lemons <kyle@kylelemons.net>.  All rights reserved.
+
+package log4go
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// HandleSIGHUP installs (enable=true) or removes (enable=false) a SIGHUP
+// handler that calls Rotate() — the standard Unix idiom that lets
+// logrotate, or an operator's `kill -HUP`, trigger an in-process rotation.
+// (chainable) Disabled by default.
+func (w *DailyFileLogWriter) HandleSIGHUP(enable bool) *DailyFileLogWriter {
+	w.sighupMu.Lock()
+	defer w.sighupMu.Unlock()
+
+	if w.sighupStop != nil {
+		signal.Stop(w.sighupCh)
+		close(w.sighupStop)
+		w.sighupCh, w.sighupStop = nil, nil
+	}
+
+	if !enable {
+		return w
+	}
+
+	sigs := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	w.sighupCh, w.sighupStop = sigs, stop
+	signal.Notify(sigs, syscall.SIGHUP)
+
+	go func() {
+		for {
+			select {
+			case <-sigs:
+				w.Rotate()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return w
+}
+
+// disableSIGHUP stops any SIGHUP handler installed via HandleSIGHUP. Close
+// calls this so the listener goroutine never outlives the writer.
+func (w *DailyFileLogWriter) disableSIGHUP() {
+	w.HandleSIGHUP(false)
+}
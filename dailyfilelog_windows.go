@@ -0,0 +1,14 @@
+//go:build windows
+
+// Copyright (C) 2010, Kyle Lemons <kyle@kylelemons.net>.  All rights reserved.
+
+package log4go
+
+// HandleSIGHUP is a no-op on Windows, which has no SIGHUP signal.
+// (chainable)
+func (w *DailyFileLogWriter) HandleSIGHUP(enable bool) *DailyFileLogWriter {
+	return w
+}
+
+// disableSIGHUP is a no-op on Windows; there is no handler to tear down.
+func (w *DailyFileLogWriter) disableSIGHUP() {}